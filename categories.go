@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CategoryMapEntry is one step in a category's progression: MinMastery is
+// the level its kata must reach before the katas after it in the map unlock.
+type CategoryMapEntry struct {
+	Name       string  `yaml:"name"`
+	MinMastery Mastery `yaml:"min_mastery"`
+}
+
+// CategoryMap is the ordered progression of katas within a category.
+type CategoryMap []CategoryMapEntry
+
+// lockedGlyph marks a kata in print()'s table that hasn't unlocked yet;
+// unlockedGlyph marks one that has (or that isn't part of any progression),
+// so the column is self-describing rather than blank.
+const (
+	lockedGlyph   = "🔒"
+	unlockedGlyph = "."
+)
+
+// unlockedNames reports, for every kata named in cm, whether it has
+// unlocked: the first entry is always unlocked, and each later entry
+// unlocks once every prior entry's kata has reached its MinMastery.
+func unlockedNames(cm CategoryMap, masteryByName map[string]Mastery) map[string]bool {
+	unlocked := make(map[string]bool, len(cm))
+	met := true
+	for _, entry := range cm {
+		unlocked[entry.Name] = met
+		if masteryByName[entry.Name] < entry.MinMastery {
+			met = false
+		}
+	}
+	return unlocked
+}
+
+// progressEntry is one kata's status within its category's progression map.
+type progressEntry struct {
+	Name     string `json:"name"`
+	Mastery  string `json:"mastery"`
+	Unlocked bool   `json:"unlocked"`
+}
+
+// progress computes, for every category with a progression map, the
+// mastery and unlock status of each kata in it, for -progress.
+func (k *katas) progress() (map[string][]progressEntry, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.load(); err != nil {
+		return nil, err
+	}
+
+	masteryByName := make(map[string]Mastery, len(k.katas))
+	for _, kt := range k.katas {
+		masteryByName[kt.Name] = mastery(kt.Repetitions, kt.Easiness)
+	}
+
+	result := make(map[string][]progressEntry, len(k.categories))
+	for category, cm := range k.categories {
+		unlocked := unlockedNames(cm, masteryByName)
+		entries := make([]progressEntry, len(cm))
+		for i, entry := range cm {
+			entries[i] = progressEntry{
+				Name:     entry.Name,
+				Mastery:  masteryByName[entry.Name].String(),
+				Unlocked: unlocked[entry.Name],
+			}
+		}
+		result[category] = entries
+	}
+
+	return result, nil
+}
+
+// pointsRecord is one append-only entry in the points log, written on
+// every markDone.
+type pointsRecord struct {
+	Timestamp     time.Time `json:"ts"`
+	Kata          string    `json:"kata"`
+	Category      string    `json:"category"`
+	MasteryBefore string    `json:"mastery_before"`
+	MasteryAfter  string    `json:"mastery_after"`
+}
+
+// pointsLogPath returns the path of the points log kept next to the katas
+// yaml file, e.g. ~/.katas.yaml -> ~/.katas.log.
+func (k *katas) pointsLogPath() string {
+	ext := filepath.Ext(k.filePath)
+	return strings.TrimSuffix(k.filePath, ext) + ".log"
+}
+
+// logPoints appends a points record for a completed review.
+func (k *katas) logPoints(rec pointsRecord) error {
+	f, err := os.OpenFile(k.pointsLogPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening points log: %w", err)
+	}
+	defer f.Close()
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshaling points record: %w", err)
+	}
+	data = append(data, '\n')
+
+	_, err = f.Write(data)
+	return err
+}
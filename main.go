@@ -2,11 +2,15 @@ package main
 
 import (
 	_ "embed"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 	"text/tabwriter"
 	"time"
 
@@ -23,8 +27,15 @@ func usage() {
 }
 
 var (
-	doneFlag = flag.String("done", "", "mark `kata` as done today")
-	initFlag = flag.Bool("init", false, "initialize "+katasFilePath())
+	doneFlag     = flag.String("done", "", "mark `kata` as done today")
+	gradeFlag    = flag.Int("grade", 4, "SM-2 `grade` (0-5) for -done, 5 being a perfect recall")
+	initFlag     = flag.Bool("init", false, "initialize the katas yaml -file with the default katas")
+	fileFlag     = flag.String("file", katasFilePath(), "path to the katas yaml `file`")
+	serveFlag    = flag.String("serve", "", "bind `address` (e.g. :8080) and serve the katas HTTP API and dashboard instead of running a one-off command")
+	tokenFlag    = flag.String("token", "", "optional bearer `token` required of -serve API requests")
+	progressFlag = flag.Bool("progress", false, "print per-category progression as JSON instead of the table")
+	logfileFlag  = flag.String("logfile", defaultLogFilePattern, "strftime-style `pattern` for the rotating event log (%Y %y %m %d %H %M %%)")
+	replayFlag   = flag.Bool("replay", false, "rebuild the yaml's Done lists from the -logfile event log")
 )
 
 func main() {
@@ -34,7 +45,7 @@ func main() {
 	flag.Usage = usage
 	flag.Parse()
 
-	katas := newKatas()
+	katas := newKatas(*fileFlag, *logfileFlag)
 
 	if *initFlag {
 		if err := katas.initConfig(); err != nil {
@@ -43,8 +54,35 @@ func main() {
 		return
 	}
 
+	if *replayFlag {
+		if err := katas.replay(*logfileFlag); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *serveFlag != "" {
+		srv := newServer(katas, *tokenFlag)
+		log.Printf("serving on %s", *serveFlag)
+		if err := http.ListenAndServe(*serveFlag, srv.routes()); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *progressFlag {
+		p, err := katas.progress()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(p); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if *doneFlag != "" {
-		if err := katas.markDone(*doneFlag); err != nil {
+		if err := katas.markDone(*doneFlag, *gradeFlag); err != nil {
 			log.Fatal(err)
 		}
 	}
@@ -53,15 +91,40 @@ func main() {
 
 // kata represents a programming exercise.
 type kata struct {
-	Name string   `yaml:"name"`
-	URL  string   `yaml:"url"`
-	Done []string `yaml:"done,omitempty"`
+	Name     string   `yaml:"name"`
+	URL      string   `yaml:"url"`
+	Category string   `yaml:"category,omitempty"`
+	Done     []string `yaml:"done,omitempty"`
+
+	// SM-2 spaced-repetition state. Reviews holds one graded attempt per
+	// practice; Easiness/Interval/Repetitions/NextDue are the scheduler's
+	// running state, recomputed by sm2 after every review.
+	Reviews     []Review `yaml:"reviews,omitempty"`
+	Easiness    float64  `yaml:"easiness,omitempty"`
+	Interval    int      `yaml:"interval,omitempty"`
+	Repetitions int      `yaml:"repetitions,omitempty"`
+	NextDue     string   `yaml:"next_due,omitempty"`
 }
 
 // katas represent a programming training.
 type katas struct {
-	filePath string
-	katas    []kata
+	filePath       string
+	logFilePattern string
+	store          Store
+	now            func() time.Time
+	katas          []kata
+	categories     map[string]CategoryMap
+
+	// mu guards load/save of the yaml file against concurrent API requests
+	// when running in -serve mode.
+	mu sync.Mutex
+}
+
+// document is the on-disk yaml shape: a list of katas plus, optionally, a
+// per-category progression map.
+type document struct {
+	Katas      []kata                 `yaml:"katas"`
+	Categories map[string]CategoryMap `yaml:"categories,omitempty"`
 }
 
 func katasFilePath() string {
@@ -69,47 +132,72 @@ func katasFilePath() string {
 	return filepath.Join(h, ".katas.yaml")
 }
 
-func newKatas() *katas {
-	return &katas{filePath: katasFilePath()}
+// newKatasFromStore builds a katas backed by an arbitrary Store, e.g. for
+// tests or an alternate backend. filePath is kept only as a display label
+// (for error messages and to derive the points log path); now defaults to
+// time.Now if nil, letting tests inject a simulated clock.
+func newKatasFromStore(store Store, filePath, logFilePattern string, now func() time.Time) *katas {
+	if now == nil {
+		now = time.Now
+	}
+	return &katas{store: store, filePath: filePath, logFilePattern: logFilePattern, now: now}
+}
+
+// newKatas builds a katas backed by the OS filesystem, as used by main.
+func newKatas(filePath, logFilePattern string) *katas {
+	return newKatasFromStore(OSStore{Path: filePath}, filePath, logFilePattern, nil)
 }
 
 func (k *katas) initConfig() error {
-	if _, err := os.Stat(k.filePath); err == nil {
+	if k.store.Exists() {
 		return fmt.Errorf("file %s already exists", k.filePath)
 	}
-	return os.WriteFile(k.filePath, defaultKatas, 0644)
+	return k.store.Write(defaultKatas)
 }
 
-// load reads katas from the file.
+// load reads katas, and their category progression maps if any, from the
+// store. Legacy files whose document root is a bare list of katas (no
+// categories) are still understood.
 func (k *katas) load() error {
-	data, err := os.ReadFile(k.filePath)
+	data, err := k.store.Read()
 	if err != nil {
 		return err
 	}
 
-	if err := yaml.Unmarshal(data, &k.katas); err != nil {
-		return fmt.Errorf("parsing file: %w", err)
+	var doc document
+	if err := yaml.Unmarshal(data, &doc); err != nil || doc.Katas == nil {
+		var legacy []kata
+		if lerr := yaml.Unmarshal(data, &legacy); lerr != nil {
+			return fmt.Errorf("parsing file: %w", err)
+		}
+		doc.Katas = legacy
+	}
+
+	k.katas = doc.Katas
+	k.categories = doc.Categories
+
+	for i := range k.katas {
+		migrateReviews(&k.katas[i])
 	}
 
 	return nil
 }
 
-// save writes katas to the file.
+// save writes katas and their category progression maps to the store.
 func (k *katas) save() error {
-	data, err := yaml.Marshal(k.katas)
+	data, err := yaml.Marshal(document{Katas: k.katas, Categories: k.categories})
 	if err != nil {
 		return fmt.Errorf("marshaling katas: %w", err)
 	}
 
-	if err := os.WriteFile(k.filePath, data, 0644); err != nil {
-		return err
-	}
-
-	return nil
+	return k.store.Write(data)
 }
 
-// print displays all katas with their status.
+// print displays all katas with their status, grouped by category.
 func (k *katas) print(doneKata string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
 	if err := k.load(); err != nil {
 		return err
 	}
@@ -121,66 +209,109 @@ func (k *katas) print(doneKata string) error {
 	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
 	defer tw.Flush()
 
-	format := "%v\t%v\t%v\t%v\t%v\n"
+	format := "%v\t%v\t%v\t%v\t%v\t%v\t%v\n"
+
+	fmt.Fprintf(tw, format, "Name", "Done", "Last done", "Due in", "Mastery", "", "URL")
+	fmt.Fprintf(tw, format, "----", "----", "---------", "------", "-------", "-", "---")
+
+	now := k.now()
+
+	masteryByName := make(map[string]Mastery, len(k.katas))
+	for _, kt := range k.katas {
+		masteryByName[kt.Name] = mastery(kt.Repetitions, kt.Easiness)
+	}
 
-	fmt.Fprintf(tw, format, "Name", "Done", "Last done", "Mastery", "URL")
-	fmt.Fprintf(tw, format, "----", "----", "---------", "-------", "---")
+	// Group katas by category, preserving the order categories first
+	// appear in the file; uncategorized katas keep their own group.
+	var categoryOrder []string
+	groups := map[string][]kata{}
+	for _, kt := range k.katas {
+		if _, ok := groups[kt.Category]; !ok {
+			categoryOrder = append(categoryOrder, kt.Category)
+		}
+		groups[kt.Category] = append(groups[kt.Category], kt)
+	}
 
 	var totalTimesDone TimesDone
-	var latestLastDone LastDone
+	latestLastDone := LastDone{now: now}
 	var totalMastery Mastery
 
-	for _, kata := range k.katas {
-		timesDone := TimesDone(len(kata.Done))
-		totalTimesDone += timesDone
+	for _, category := range categoryOrder {
+		if category != "" {
+			fmt.Fprintf(tw, format, category+":", "", "", "", "", "", "")
+		}
 
-		var lastDone LastDone
-		for _, d := range kata.Done {
-			t, err := time.Parse("2006-01-02", d)
-			if err != nil {
-				log.Printf("parsing kata %q in %s: %v", kata.Name, katasFilePath(), err)
-				continue
+		unlocked := unlockedNames(k.categories[category], masteryByName)
+
+		// Sort overdue-first within the group: katas with no NextDue yet
+		// (never reviewed) sink to the bottom, alongside mastered ones.
+		rows := append([]kata(nil), groups[category]...)
+		sort.SliceStable(rows, func(i, j int) bool {
+			di, dj := dueIn(rows[i].NextDue, now), dueIn(rows[j].NextDue, now)
+			if di.set != dj.set {
+				return di.set
 			}
-			if t.After(lastDone.t) {
-				lastDone.t = t
+			return di.days < dj.days
+		})
+
+		for _, kata := range rows {
+			timesDone := TimesDone(len(kata.Done))
+			totalTimesDone += timesDone
+
+			lastDone := LastDone{now: now}
+			for _, d := range kata.Done {
+				t, err := time.Parse("2006-01-02", d)
+				if err != nil {
+					log.Printf("parsing kata %q in %s: %v", kata.Name, katasFilePath(), err)
+					continue
+				}
+				if t.After(lastDone.t) {
+					lastDone.t = t
+				}
+				if t.After(latestLastDone.t) {
+					latestLastDone.t = t
+				}
 			}
-			if t.After(latestLastDone.t) {
-				latestLastDone.t = t
+
+			kataName := kata.Name
+			if kataName == doneKata {
+				kataName = "> " + kataName
 			}
-		}
 
-		kataName := kata.Name
-		if kataName == doneKata {
-			kataName = "> " + kataName
-		}
+			lock := unlockedGlyph
+			if _, isMapped := unlocked[kata.Name]; isMapped && !unlocked[kata.Name] {
+				lock = lockedGlyph
+			}
 
-		kataMastery := mastery(int(timesDone), lastDone.t)
-		totalMastery += kataMastery
+			kataMastery := mastery(kata.Repetitions, kata.Easiness)
+			totalMastery += kataMastery
 
-		fmt.Fprintf(tw, format, kataName, timesDone, lastDone, kataMastery, kata.URL)
+			fmt.Fprintf(tw, format, kataName, timesDone, lastDone, dueIn(kata.NextDue, now), kataMastery, lock, kata.URL)
+		}
 	}
 
-	fmt.Fprintf(tw, format, "----", "----", "---------", "-------", "---")
+	fmt.Fprintf(tw, format, "----", "----", "---------", "------", "-------", "-", "---")
 	var avgMastery Mastery
 	if len(k.katas) > 0 {
 		avgMastery = Mastery(int(totalMastery) / len(k.katas))
 	} else {
 		avgMastery = 0
 	}
-	fmt.Fprintf(tw, format, len(k.katas), totalTimesDone, latestLastDone, avgMastery, "")
+	fmt.Fprintf(tw, format, len(k.katas), totalTimesDone, latestLastDone, "", avgMastery, "", "")
 
 	return nil
 }
 
 type LastDone struct {
-	t time.Time
+	t   time.Time
+	now time.Time
 }
 
 func (ld LastDone) String() string {
 	if ld.t.IsZero() {
 		return "never"
 	}
-	daysAgo := int(time.Since(ld.t).Hours() / 24)
+	daysAgo := int(ld.now.Sub(ld.t).Hours() / 24)
 	return fmt.Sprintf("%d days ago", daysAgo)
 }
 
@@ -190,13 +321,22 @@ func (td TimesDone) String() string {
 	return fmt.Sprintf("%dx", td)
 }
 
-// markDone marks a kata as completed today.
-func (k *katas) markDone(name string) error {
+// markDone marks a kata as completed today, grading it 0-5 for the SM-2
+// scheduler (5 being a perfect, effortless recall).
+func (k *katas) markDone(name string, grade int) error {
+	if grade < 0 || grade > 5 {
+		return fmt.Errorf("grade %d out of range, want 0-5", grade)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
 	if err := k.load(); err != nil {
 		return err
 	}
 
-	today := time.Now().Format("2006-01-02")
+	now := k.now()
+	today := now.Format("2006-01-02")
 
 	for i, kata := range k.katas {
 		if kata.Name == name {
@@ -205,8 +345,91 @@ func (k *katas) markDone(name string) error {
 				return fmt.Errorf("kata %s already marked as done today\n", name)
 			}
 
+			masteryBefore := mastery(kata.Repetitions, kata.Easiness)
+
 			k.katas[i].Done = append(kata.Done, today)
-			return k.save()
+			k.katas[i].Reviews = append(kata.Reviews, Review{Date: today, Grade: grade})
+			sm2(&k.katas[i], grade, now)
+
+			if err := k.save(); err != nil {
+				return err
+			}
+
+			masteryAfter := mastery(k.katas[i].Repetitions, k.katas[i].Easiness)
+
+			if err := k.logPoints(pointsRecord{
+				Timestamp:     now,
+				Kata:          name,
+				Category:      kata.Category,
+				MasteryBefore: masteryBefore.String(),
+				MasteryAfter:  masteryAfter.String(),
+			}); err != nil {
+				return err
+			}
+
+			return logEvent(k.logFilePattern, now, eventRecord{
+				Timestamp: now.Format(time.RFC3339),
+				Event:     "done",
+				Kata:      name,
+				TimesDone: len(k.katas[i].Done),
+				Mastery:   masteryAfter.String(),
+			})
+		}
+	}
+
+	return fmt.Errorf("kata %s not found in %s", name, k.filePath)
+}
+
+// addKata adds a new kata, failing if one with the same name already exists.
+func (k *katas) addKata(name, url string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.load(); err != nil {
+		return err
+	}
+
+	for _, existing := range k.katas {
+		if existing.Name == name {
+			return fmt.Errorf("kata %s already exists", name)
+		}
+	}
+
+	k.katas = append(k.katas, kata{Name: name, URL: url})
+	if err := k.save(); err != nil {
+		return err
+	}
+
+	now := k.now()
+	return logEvent(k.logFilePattern, now, eventRecord{
+		Timestamp: now.Format(time.RFC3339),
+		Event:     "add",
+		Kata:      name,
+	})
+}
+
+// removeKata removes a kata by name.
+func (k *katas) removeKata(name string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.load(); err != nil {
+		return err
+	}
+
+	for i, existing := range k.katas {
+		if existing.Name == name {
+			k.katas = append(k.katas[:i], k.katas[i+1:]...)
+			if err := k.save(); err != nil {
+				return err
+			}
+
+			now := k.now()
+			return logEvent(k.logFilePattern, now, eventRecord{
+				Timestamp: now.Format(time.RFC3339),
+				Event:     "remove",
+				Kata:      name,
+			})
 		}
 	}
 
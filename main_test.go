@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// memStore is an in-memory Store for tests, so they never touch $HOME.
+type memStore struct {
+	data   []byte
+	exists bool
+}
+
+func (s *memStore) Read() ([]byte, error) {
+	if !s.exists {
+		return nil, fmt.Errorf("memStore: no data")
+	}
+	return s.data, nil
+}
+
+func (s *memStore) Write(data []byte) error {
+	s.data = data
+	s.exists = true
+	return nil
+}
+
+func (s *memStore) Exists() bool { return s.exists }
+
+func newMemStoreWithKatas(t *testing.T, ks []kata) *memStore {
+	t.Helper()
+	data, err := yaml.Marshal(document{Katas: ks})
+	if err != nil {
+		t.Fatalf("marshaling test fixture: %v", err)
+	}
+	return &memStore{data: data, exists: true}
+}
+
+// captureStdout redirects os.Stdout for the duration of fn and returns
+// what was written to it.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orig := os.Stdout
+	os.Stdout = w
+	fn()
+	w.Close()
+	os.Stdout = orig
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestInitConfigRefusesOverwrite(t *testing.T) {
+	store := &memStore{exists: true, data: []byte("katas: []\n")}
+	k := newKatasFromStore(store, filepath.Join(t.TempDir(), "katas.yaml"), "", nil)
+
+	if err := k.initConfig(); err == nil {
+		t.Fatal("expected initConfig to refuse to overwrite an existing file")
+	}
+}
+
+func TestInitConfig(t *testing.T) {
+	store := &memStore{}
+	k := newKatasFromStore(store, filepath.Join(t.TempDir(), "katas.yaml"), "", nil)
+
+	if err := k.initConfig(); err != nil {
+		t.Fatalf("initConfig: %v", err)
+	}
+	if !store.exists {
+		t.Fatal("expected initConfig to write to the store")
+	}
+}
+
+func TestMarkDone(t *testing.T) {
+	cases := []struct {
+		name     string
+		kata     string
+		grade    int
+		wantErr  bool
+		wantDone []string
+		wantReps int
+	}{
+		{name: "marks a known kata done", kata: "fizzbuzz", grade: 5, wantDone: []string{"2024-01-10"}, wantReps: 1},
+		{name: "a low grade does not build a streak", kata: "fizzbuzz", grade: 2, wantDone: []string{"2024-01-10"}, wantReps: 0},
+		{name: "an unknown kata is an error", kata: "missing", grade: 5, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+			store := newMemStoreWithKatas(t, []kata{{Name: "fizzbuzz", URL: "https://example.com/fizzbuzz"}})
+			k := newKatasFromStore(store, filepath.Join(t.TempDir(), "katas.yaml"), "", func() time.Time { return now })
+
+			err := k.markDone(tc.kata, tc.grade)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("markDone: %v", err)
+			}
+
+			if err := k.load(); err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if !reflect.DeepEqual(k.katas[0].Done, tc.wantDone) {
+				t.Errorf("Done = %v, want %v", k.katas[0].Done, tc.wantDone)
+			}
+			if k.katas[0].Repetitions != tc.wantReps {
+				t.Errorf("Repetitions = %d, want %d", k.katas[0].Repetitions, tc.wantReps)
+			}
+		})
+	}
+}
+
+func TestMarkDoneAlreadyDoneToday(t *testing.T) {
+	now := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	store := newMemStoreWithKatas(t, []kata{{Name: "fizzbuzz", URL: "https://example.com/fizzbuzz"}})
+	k := newKatasFromStore(store, filepath.Join(t.TempDir(), "katas.yaml"), "", func() time.Time { return now })
+
+	if err := k.markDone("fizzbuzz", 5); err != nil {
+		t.Fatalf("markDone: %v", err)
+	}
+	if err := k.markDone("fizzbuzz", 5); err == nil {
+		t.Fatal("expected an error marking the same kata done twice in one day")
+	}
+}
+
+func TestPrintFormatting(t *testing.T) {
+	now := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	store := newMemStoreWithKatas(t, []kata{{Name: "fizzbuzz", URL: "https://example.com/fizzbuzz"}})
+	k := newKatasFromStore(store, filepath.Join(t.TempDir(), "katas.yaml"), "", func() time.Time { return now })
+
+	out := captureStdout(t, func() {
+		if err := k.print(""); err != nil {
+			t.Fatalf("print: %v", err)
+		}
+	})
+
+	for _, want := range []string{"Name", "Due in", "Mastery", "fizzbuzz", "never"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestMasteryDecayAfterFailedReview(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := newMemStoreWithKatas(t, []kata{{Name: "fizzbuzz", URL: "https://example.com/fizzbuzz"}})
+	k := newKatasFromStore(store, filepath.Join(t.TempDir(), "katas.yaml"), "", func() time.Time { return clock })
+
+	for _, grade := range []int{5, 5, 5} {
+		if err := k.markDone("fizzbuzz", grade); err != nil {
+			t.Fatalf("markDone: %v", err)
+		}
+		clock = clock.AddDate(0, 0, 1)
+	}
+
+	if err := k.load(); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	before := mastery(k.katas[0].Repetitions, k.katas[0].Easiness)
+	if before < 3 {
+		t.Fatalf("expected mastery to build up after 3 good reviews, got %v", before)
+	}
+
+	// A failed review resets the SM-2 repetition streak, which should
+	// pull the displayed mastery back down.
+	if err := k.markDone("fizzbuzz", 1); err != nil {
+		t.Fatalf("markDone: %v", err)
+	}
+	if err := k.load(); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if k.katas[0].Repetitions != 0 {
+		t.Fatalf("expected a failed review to reset Repetitions to 0, got %d", k.katas[0].Repetitions)
+	}
+	after := mastery(k.katas[0].Repetitions, k.katas[0].Easiness)
+	if after >= before {
+		t.Fatalf("expected mastery to decay after a failed review: before=%v after=%v", before, after)
+	}
+}
+
+// TestDueInAcrossSimulatedClocks exercises dueIn with an injected clock that
+// advances past a kata's NextDue date, the way the CLI's real clock does
+// between runs, rather than sitting fixed at midnight.
+func TestDueInAcrossSimulatedClocks(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := newMemStoreWithKatas(t, []kata{{Name: "fizzbuzz", URL: "https://example.com/fizzbuzz"}})
+	k := newKatasFromStore(store, filepath.Join(t.TempDir(), "katas.yaml"), "", func() time.Time { return clock })
+
+	if err := k.markDone("fizzbuzz", 4); err != nil {
+		t.Fatalf("markDone: %v", err)
+	}
+	if err := k.load(); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	nextDue := k.katas[0].NextDue
+
+	if got := dueIn(nextDue, clock).String(); got != "in 1d" {
+		t.Fatalf("dueIn right after review = %q, want %q", got, "in 1d")
+	}
+
+	// Advance the clock, including past midnight into the afternoon, to
+	// the kata's due date and confirm it now reads as due today rather
+	// than still "in 1d".
+	clock = clock.AddDate(0, 0, 1).Add(15 * time.Hour)
+	if got := dueIn(nextDue, clock).String(); got != "today" {
+		t.Fatalf("dueIn on the due date = %q, want %q", got, "today")
+	}
+
+	// Advance one more day and it should read as overdue.
+	clock = clock.AddDate(0, 0, 1)
+	if got := dueIn(nextDue, clock).String(); got != "1d overdue" {
+		t.Fatalf("dueIn the day after = %q, want %q", got, "1d overdue")
+	}
+}
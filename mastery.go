@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+	"math"
 	"time"
 )
 
@@ -21,43 +23,118 @@ func (m Mastery) String() string {
 	return levels[m]
 }
 
-func mastery(timesDone int, lastDone time.Time) Mastery {
-	if timesDone <= 0 {
+// mastery derives a displayed mastery level from a kata's SM-2 state: the
+// number of consecutive successful repetitions, capped and nudged down a
+// notch when the easiness factor shows the kata is still shaky.
+func mastery(repetitions int, easiness float64) Mastery {
+	if repetitions <= 0 {
 		return 0
 	}
 
-	var base int
-	switch {
-	case timesDone <= 2:
-		base = 1
-	case timesDone <= 5:
-		base = 2
-	case timesDone <= 9:
-		base = 3
-	case timesDone <= 14:
-		base = 4
-	default:
-		base = 5
+	level := repetitions
+	if level > 5 {
+		level = 5
+	}
+	if easiness < 1.8 && level > 1 {
+		level--
 	}
 
-	// decay by recency
-	var decay int
-	daysAgo := int(time.Since(lastDone).Hours() / 24)
-	switch {
-	case daysAgo <= 3:
-		decay = 0
-	case daysAgo <= 7:
-		decay = 1
-	case daysAgo <= 14:
-		decay = 2
-	default:
-		decay = 3
+	return Mastery(level)
+}
+
+// Review is a single graded attempt at a kata, as used by the SM-2 scheduler.
+type Review struct {
+	Date  string `yaml:"date"`
+	Grade int    `yaml:"grade"`
+}
+
+const defaultEasiness = 2.5
+
+// sm2 applies one step of the SM-2 spaced-repetition algorithm to k for a
+// review graded today with the given quality (0-5), updating Easiness,
+// Interval, Repetitions and NextDue in place.
+func sm2(k *kata, grade int, today time.Time) {
+	if k.Easiness == 0 {
+		k.Easiness = defaultEasiness
 	}
 
-	level := base - decay
-	if level < 0 {
-		level = 0
+	if grade < 3 {
+		k.Repetitions = 0
+		k.Interval = 1
+	} else {
+		k.Repetitions++
+		switch k.Repetitions {
+		case 1:
+			k.Interval = 1
+		case 2:
+			k.Interval = 6
+		default:
+			k.Interval = int(math.Round(float64(k.Interval) * k.Easiness))
+		}
 	}
 
-	return Mastery(level)
+	k.Easiness += 0.1 - float64(5-grade)*(0.08+float64(5-grade)*0.02)
+	if k.Easiness < 1.3 {
+		k.Easiness = 1.3
+	}
+
+	k.NextDue = today.AddDate(0, 0, k.Interval).Format("2006-01-02")
+}
+
+// migrateReviews seeds Reviews/Easiness/Interval/Repetitions/NextDue from the
+// legacy Done list the first time a kata without SM-2 state is loaded,
+// assuming grade 4 (a normal, unremarkable pass) for every historical entry.
+func migrateReviews(k *kata) {
+	if len(k.Reviews) > 0 || len(k.Done) == 0 {
+		return
+	}
+
+	for _, d := range k.Done {
+		k.Reviews = append(k.Reviews, Review{Date: d, Grade: 4})
+
+		t, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			continue
+		}
+		sm2(k, 4, t)
+	}
+}
+
+// DueIn formats the number of days until (positive) or since (negative,
+// overdue) a kata's NextDue date.
+type DueIn struct {
+	days int
+	set  bool
+}
+
+func dueIn(nextDue string, today time.Time) DueIn {
+	if nextDue == "" {
+		return DueIn{}
+	}
+	t, err := time.Parse("2006-01-02", nextDue)
+	if err != nil {
+		return DueIn{}
+	}
+
+	// Compare calendar days, not wall-clock hours: today may be any time
+	// of day, but NextDue is always midnight, so diffing the raw times
+	// under-reports every future date by up to a day.
+	midnight := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+	days := int(t.Sub(midnight).Hours() / 24)
+
+	return DueIn{days: days, set: true}
+}
+
+func (d DueIn) String() string {
+	if !d.set {
+		return "-"
+	}
+	switch {
+	case d.days < 0:
+		return fmt.Sprintf("%dd overdue", -d.days)
+	case d.days == 0:
+		return "today"
+	default:
+		return fmt.Sprintf("in %dd", d.days)
+	}
 }
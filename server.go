@@ -0,0 +1,178 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//go:embed web/index.html
+var dashboardHTML []byte
+
+// server exposes a katas store over a small JSON API plus a browser
+// dashboard, for use with -serve.
+type server struct {
+	katas *katas
+	token string
+}
+
+func newServer(k *katas, token string) *server {
+	return &server{katas: k, token: token}
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	// The dashboard itself is exempt from withAuth: it's the only way a
+	// browser can learn it needs a token in the first place, and it
+	// attaches that token to every /api/ call it makes on its own.
+	mux.HandleFunc("/", s.handleIndex)
+	mux.Handle("/api/katas", s.withAuth(http.HandlerFunc(s.handleKatas)))
+	mux.Handle("/api/katas/", s.withAuth(http.HandlerFunc(s.handleKata)))
+	return mux
+}
+
+// withAuth requires a matching bearer token on every request when one is
+// configured; it is a no-op otherwise.
+func (s *server) withAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" && r.Header.Get("Authorization") != "Bearer "+s.token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardHTML)
+}
+
+// kataView is the JSON shape of a kata returned by the API: the stored
+// fields plus the mastery and due-in values print() computes for display.
+// Category and lock status are not included, and last_done is the date
+// itself rather than a count of days ago.
+type kataView struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Done     int    `json:"times_done"`
+	LastDone string `json:"last_done,omitempty"`
+	Mastery  string `json:"mastery"`
+	NextDue  string `json:"next_due,omitempty"`
+	DueIn    string `json:"due_in"`
+}
+
+func newKataView(k kata, now time.Time) kataView {
+	var lastDone string
+	for _, d := range k.Done {
+		if d > lastDone {
+			lastDone = d
+		}
+	}
+	return kataView{
+		Name:     k.Name,
+		URL:      k.URL,
+		Done:     len(k.Done),
+		LastDone: lastDone,
+		Mastery:  mastery(k.Repetitions, k.Easiness).String(),
+		NextDue:  k.NextDue,
+		DueIn:    dueIn(k.NextDue, now).String(),
+	}
+}
+
+func (s *server) handleKatas(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.katas.mu.Lock()
+		defer s.katas.mu.Unlock()
+
+		if err := s.katas.load(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		now := s.katas.now()
+		views := make([]kataView, len(s.katas.katas))
+		for i, k := range s.katas.katas {
+			views[i] = newKataView(k, now)
+		}
+		writeJSON(w, views)
+
+	case http.MethodPost:
+		var req struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.katas.addKata(req.Name, req.URL); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) handleKata(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/katas/")
+	if name == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if done, ok := strings.CutSuffix(name, "/done"); ok {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		grade := 4
+		if r.ContentLength > 0 {
+			var req struct {
+				Grade int `json:"grade"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			grade = req.Grade
+		}
+
+		if err := s.katas.markDone(done, grade); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodDelete:
+		if err := s.katas.removeKata(name); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("encoding response: %v", err)
+	}
+}
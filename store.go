@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// Store persists the raw katas yaml, behind a small interface so katas
+// doesn't have to talk to the OS filesystem directly and can be tested,
+// or backed by an alternate store, without touching $HOME.
+type Store interface {
+	Read() ([]byte, error)
+	Write([]byte) error
+	Exists() bool
+}
+
+// OSStore is the default Store, backed by a file on disk.
+type OSStore struct {
+	Path string
+}
+
+func (s OSStore) Read() ([]byte, error) {
+	return os.ReadFile(s.Path)
+}
+
+func (s OSStore) Write(data []byte) error {
+	return os.WriteFile(s.Path, data, 0644)
+}
+
+func (s OSStore) Exists() bool {
+	_, err := os.Stat(s.Path)
+	return err == nil
+}
+
+// HTTPStore is a Store backed by an HTTP endpoint that serves and accepts
+// a yaml blob via GET and PUT, letting the same binary sync katas across
+// machines via any object store that speaks HTTP PUT.
+type HTTPStore struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s HTTPStore) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+func (s HTTPStore) Read() ([]byte, error) {
+	resp, err := s.client().Get(s.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, os.ErrNotExist
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", s.URL, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s HTTPStore) Write(data []byte) error {
+	req, err := http.NewRequest(http.MethodPut, s.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("PUT %s: %s", s.URL, resp.Status)
+	}
+	return nil
+}
+
+func (s HTTPStore) Exists() bool {
+	resp, err := s.client().Head(s.URL)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}